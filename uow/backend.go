@@ -0,0 +1,47 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+)
+
+// BackendTx is a transaction opened by a Backend.
+//
+// Commit and Rollback finalize it. Handle returns the underlying driver
+// transaction (e.g. *sql.Tx, pgx.Tx, *gorm.DB) that a RepositoryFactory uses
+// to build a repository.
+type BackendTx interface {
+	Commit() error
+	Rollback() error
+	Handle() any
+}
+
+// Backend begins transactions for a specific driver, decoupling UnitOfWork
+// from database/sql. See the uow/backend subpackages for implementations,
+// and NewUnitOfWorkWithBackend.
+type Backend interface {
+	Begin(ctx context.Context, opts *sql.TxOptions) (BackendTx, error)
+}
+
+// DBSystem may be implemented by a Backend to report the OpenTelemetry
+// db.system value for the driver it wraps, e.g. "postgresql" for the pgx
+// backend (see
+// https://opentelemetry.io/docs/specs/semconv/database/database-spans/). A
+// Backend that doesn't implement it is reported as "other_sql", since
+// database/sql and driver-agnostic backends like sqldb, gorm and ent don't
+// know their underlying dialect.
+type DBSystem interface {
+	DBSystem() string
+}
+
+// SavepointTx may be implemented by a BackendTx whose driver supports SQL
+// savepoints, letting Do nest inside an already active transaction by
+// opening a SAVEPOINT on it instead of requiring a fresh one. A Backend
+// whose BackendTx doesn't implement it doesn't support nested Do: Do called
+// from within another Do's fn fails with ErrNestedDoNotSupported instead of
+// nesting. The default Backend, built from a *sql.DB, always implements it.
+type SavepointTx interface {
+	Savepoint(ctx context.Context, name string) error
+	ReleaseSavepoint(ctx context.Context, name string) error
+	RollbackToSavepoint(ctx context.Context, name string) error
+}
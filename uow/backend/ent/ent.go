@@ -0,0 +1,60 @@
+// Package ent implements uow.Backend on top of a generated ent client.
+//
+// entgo.io/ent generates a distinct Client and Tx type per project, so
+// Backend is generic over them instead of depending on entgo.io/ent itself:
+// any generated client with a BeginTx method shaped like Client[T] works.
+package ent
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sesaquecruz/go-unit-of-work/uow"
+)
+
+// Tx is the part of a generated ent client's transaction type used here.
+type Tx interface {
+	Commit() error
+	Rollback() error
+}
+
+// Client is the part of a generated ent client used here.
+type Client[T Tx] interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (T, error)
+}
+
+// Backend begins transactions on a generated ent Client.
+type Backend[T Tx] struct {
+	client Client[T]
+}
+
+// Create a new backend. Return a pointer to a backend.
+func NewBackend[T Tx](client Client[T]) *Backend[T] {
+	return &Backend[T]{client: client}
+}
+
+// Begin a transaction on the underlying client.
+func (b *Backend[T]) Begin(ctx context.Context, opts *sql.TxOptions) (uow.BackendTx, error) {
+	tx, err := b.client.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backendTx[T]{tx: tx}, nil
+}
+
+type backendTx[T Tx] struct {
+	tx T
+}
+
+func (t *backendTx[T]) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *backendTx[T]) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func (t *backendTx[T]) Handle() any {
+	return t.tx
+}
@@ -0,0 +1,48 @@
+// Package gorm implements uow.Backend on top of gorm.io/gorm, for
+// repositories built around a *gorm.DB scoped to a transaction.
+package gorm
+
+import (
+	"context"
+	"database/sql"
+
+	"gorm.io/gorm"
+
+	"github.com/sesaquecruz/go-unit-of-work/uow"
+)
+
+// Backend begins transactions on a *gorm.DB.
+type Backend struct {
+	db *gorm.DB
+}
+
+// Create a new backend. Return a pointer to a backend.
+func NewBackend(db *gorm.DB) *Backend {
+	return &Backend{db: db}
+}
+
+// Begin a transaction on the underlying *gorm.DB.
+func (b *Backend) Begin(ctx context.Context, opts *sql.TxOptions) (uow.BackendTx, error) {
+	tx := b.db.WithContext(ctx).Begin(opts)
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+
+	return &backendTx{tx: tx}, nil
+}
+
+type backendTx struct {
+	tx *gorm.DB
+}
+
+func (t *backendTx) Commit() error {
+	return t.tx.Commit().Error
+}
+
+func (t *backendTx) Rollback() error {
+	return t.tx.Rollback().Error
+}
+
+func (t *backendTx) Handle() any {
+	return t.tx
+}
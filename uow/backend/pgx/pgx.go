@@ -0,0 +1,82 @@
+// Package pgx implements uow.Backend on top of github.com/jackc/pgx/v5, for
+// repositories built around pgx.Tx instead of database/sql.
+package pgx
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"github.com/sesaquecruz/go-unit-of-work/uow"
+)
+
+// Backend begins transactions on a *pgxpool.Pool.
+type Backend struct {
+	pool *pgxpool.Pool
+}
+
+// Create a new backend. Return a pointer to a backend.
+func NewBackend(pool *pgxpool.Pool) *Backend {
+	return &Backend{pool: pool}
+}
+
+// DBSystem reports "postgresql" as this Backend's OpenTelemetry db.system
+// value; see uow.DBSystem.
+func (b *Backend) DBSystem() string {
+	return "postgresql"
+}
+
+// Begin a transaction on the underlying pool, translating opts into the
+// nearest equivalent pgx.TxOptions.
+func (b *Backend) Begin(ctx context.Context, opts *sql.TxOptions) (uow.BackendTx, error) {
+	tx, err := b.pool.BeginTx(ctx, toPgxTxOptions(opts))
+	if err != nil {
+		return nil, err
+	}
+
+	return &backendTx{ctx: ctx, tx: tx}, nil
+}
+
+func toPgxTxOptions(opts *sql.TxOptions) pgx.TxOptions {
+	if opts == nil {
+		return pgx.TxOptions{}
+	}
+
+	pgxOpts := pgx.TxOptions{}
+
+	switch opts.Isolation {
+	case sql.LevelSerializable:
+		pgxOpts.IsoLevel = pgx.Serializable
+	case sql.LevelRepeatableRead:
+		pgxOpts.IsoLevel = pgx.RepeatableRead
+	case sql.LevelReadCommitted:
+		pgxOpts.IsoLevel = pgx.ReadCommitted
+	case sql.LevelReadUncommitted:
+		pgxOpts.IsoLevel = pgx.ReadUncommitted
+	}
+
+	if opts.ReadOnly {
+		pgxOpts.AccessMode = pgx.ReadOnly
+	}
+
+	return pgxOpts
+}
+
+type backendTx struct {
+	ctx context.Context
+	tx  pgx.Tx
+}
+
+func (t *backendTx) Commit() error {
+	return t.tx.Commit(t.ctx)
+}
+
+func (t *backendTx) Rollback() error {
+	return t.tx.Rollback(t.ctx)
+}
+
+func (t *backendTx) Handle() any {
+	return t.tx
+}
@@ -0,0 +1,67 @@
+// Package sqldb implements uow.Backend on top of database/sql, the same
+// driver uow.NewUnitOfWork already uses directly as its default backend. It
+// exists so callers building on uow.NewUnitOfWorkWithBackend can target
+// database/sql explicitly, the same way they would pgx or gorm.
+package sqldb
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sesaquecruz/go-unit-of-work/uow"
+)
+
+// Backend begins transactions on a *sql.DB.
+type Backend struct {
+	db *sql.DB
+}
+
+// Create a new backend. Return a pointer to a backend.
+func NewBackend(db *sql.DB) *Backend {
+	return &Backend{db: db}
+}
+
+// Begin a transaction on the underlying *sql.DB.
+func (b *Backend) Begin(ctx context.Context, opts *sql.TxOptions) (uow.BackendTx, error) {
+	tx, err := b.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &backendTx{tx: tx}, nil
+}
+
+type backendTx struct {
+	tx *sql.Tx
+}
+
+func (t *backendTx) Commit() error {
+	return t.tx.Commit()
+}
+
+func (t *backendTx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+func (t *backendTx) Handle() any {
+	return t.tx
+}
+
+// Savepoint, ReleaseSavepoint and RollbackToSavepoint implement
+// uow.SavepointTx, so a Do nested inside another Do opens a SAVEPOINT on
+// this *sql.Tx instead of failing with uow.ErrNestedDoNotSupported.
+
+func (t *backendTx) Savepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+func (t *backendTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func (t *backendTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
@@ -0,0 +1,195 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Fake backend, for exercising NewUnitOfWorkWithBackend without a real driver.
+// fakeBackendTx does not implement SavepointTx, so it also exercises the
+// ErrNestedDoNotSupported path.
+type fakeBackendTx struct {
+	committed  bool
+	rolledBack bool
+	commitErr  error
+}
+
+func (t *fakeBackendTx) Commit() error {
+	t.committed = true
+	return t.commitErr
+}
+
+func (t *fakeBackendTx) Rollback() error {
+	t.rolledBack = true
+	return nil
+}
+
+func (t *fakeBackendTx) Handle() any {
+	return t
+}
+
+type fakeBackend struct {
+	begins    int
+	beginErr  error
+	commitErr error
+	lastTx    *fakeBackendTx
+}
+
+func (b *fakeBackend) Begin(ctx context.Context, opts *sql.TxOptions) (BackendTx, error) {
+	b.begins++
+	if b.beginErr != nil {
+		return nil, b.beginErr
+	}
+
+	b.lastTx = &fakeBackendTx{commitErr: b.commitErr}
+	return b.lastTx, nil
+}
+
+type fakeRepository struct {
+	handle any
+}
+
+func Test_UnitOfWork_WithBackend_Do_WhenFnSucceeds(t *testing.T) {
+	backend := &fakeBackend{}
+	uow := NewUnitOfWorkWithBackend(backend)
+
+	uow.Register("FakeRepository", func(handle any) Repository {
+		return &fakeRepository{handle: handle}
+	})
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		repository, err := tx.Get("FakeRepository")
+		if err != nil {
+			return err
+		}
+
+		assert.Same(t, backend.lastTx, repository.(*fakeRepository).handle)
+		return nil
+	})
+
+	assert.Nil(t, err)
+	require.NotNil(t, backend.lastTx)
+	assert.True(t, backend.lastTx.committed)
+	assert.False(t, backend.lastTx.rolledBack)
+}
+
+func Test_UnitOfWork_WithBackend_Do_WhenFnFails(t *testing.T) {
+	backend := &fakeBackend{}
+	uow := NewUnitOfWorkWithBackend(backend)
+
+	fnErr := errors.New("some error")
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return fnErr
+	})
+
+	assert.ErrorIs(t, err, fnErr)
+	require.NotNil(t, backend.lastTx)
+	assert.False(t, backend.lastTx.committed)
+	assert.True(t, backend.lastTx.rolledBack)
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithRetry_WhenRetryableErrorSucceedsEventually(t *testing.T) {
+	backend := &fakeBackend{}
+	uow := NewUnitOfWorkWithBackend(backend)
+
+	attempts := 0
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("Error 1213: Deadlock found when trying to get lock")
+		}
+
+		return nil
+	}, WithRetry(5, func(attempt int) time.Duration { return 0 }))
+
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.Equal(t, 3, backend.begins)
+}
+
+func Test_UnitOfWork_WithBackend_Do_RunsOnCommitAfterCommit(t *testing.T) {
+	backend := &fakeBackend{}
+	uow := NewUnitOfWorkWithBackend(backend)
+
+	var order []string
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		tx.OnCommit(func(ctx context.Context) error {
+			order = append(order, "onCommit")
+			return nil
+		})
+		order = append(order, "fn")
+		return nil
+	})
+
+	assert.Nil(t, err)
+	require.NotNil(t, backend.lastTx)
+	assert.True(t, backend.lastTx.committed)
+	assert.Equal(t, []string{"fn", "onCommit"}, order)
+}
+
+func Test_UnitOfWork_WithBackend_Do_WhenBeforeCommitFailsRollsBack(t *testing.T) {
+	backend := &fakeBackend{}
+	uow := NewUnitOfWorkWithBackend(backend)
+
+	beforeCommitErr := errors.New("before commit error")
+	onCommitCalled := false
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		tx.OnBeforeCommit(func(ctx context.Context) error {
+			return beforeCommitErr
+		})
+		tx.OnCommit(func(ctx context.Context) error {
+			onCommitCalled = true
+			return nil
+		})
+		return nil
+	})
+
+	assert.ErrorIs(t, err, beforeCommitErr)
+	require.NotNil(t, backend.lastTx)
+	assert.False(t, backend.lastTx.committed)
+	assert.True(t, backend.lastTx.rolledBack)
+	assert.False(t, onCommitCalled)
+}
+
+func Test_UnitOfWork_WithBackend_DoAs_PassesScopeToScopedFactory(t *testing.T) {
+	backend := &fakeBackend{}
+	uow := NewUnitOfWorkWithBackend(backend)
+
+	var gotScope Scope
+
+	uow.RegisterScoped("FakeRepository", func(handle any, scope Scope) Repository {
+		gotScope = scope
+		return &fakeRepository{handle: handle}
+	})
+
+	err := uow.DoAs(context.Background(), "tenant-1", func(ctx context.Context, tx TX) error {
+		_, err := tx.Get("FakeRepository")
+		assert.Equal(t, TenantID("tenant-1"), tx.Scope().TenantID())
+		return err
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, TenantID("tenant-1"), gotScope.TenantID())
+}
+
+func Test_UnitOfWork_WithBackend_Do_NestedDo_ReturnsErrNestedDoNotSupported(t *testing.T) {
+	backend := &fakeBackend{}
+	uow := NewUnitOfWorkWithBackend(backend)
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return uow.Do(ctx, func(ctx context.Context, tx TX) error {
+			return nil
+		})
+	})
+
+	assert.ErrorIs(t, err, ErrNestedDoNotSupported)
+}
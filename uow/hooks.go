@@ -0,0 +1,105 @@
+package uow
+
+import (
+	"context"
+	"sync"
+)
+
+// Hook is a callback registered on a TX via OnBeforeCommit, OnCommit or
+// OnRollback.
+type Hook func(ctx context.Context) error
+
+// hookSet collects the hooks registered on a TX so the Do call that owns
+// its transaction can run them at the right point in the transaction's
+// lifecycle. A nested Do (see beginOrNest) gets its own hookSet, scoped to
+// its own savepoint: mergeInto promotes it to the outer Do's hookSet on its
+// own success, since only the outermost Do's commit is durable; on its own
+// failure it is fired and discarded instead, without ever reaching the
+// outer set. See UnitOfWork.doOnce.
+type hookSet struct {
+	mu           sync.Mutex
+	beforeCommit []Hook
+	onCommit     []Hook
+	onRollback   []Hook
+}
+
+func newHookSet() *hookSet {
+	return &hookSet{}
+}
+
+func (h *hookSet) addBeforeCommit(hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.beforeCommit = append(h.beforeCommit, hook)
+}
+
+func (h *hookSet) addOnCommit(hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onCommit = append(h.onCommit, hook)
+}
+
+func (h *hookSet) addOnRollback(hook Hook) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onRollback = append(h.onRollback, hook)
+}
+
+// runBeforeCommit runs the registered before-commit hooks, in registration
+// order, stopping at the first error so it can abort the commit.
+func (h *hookSet) runBeforeCommit(ctx context.Context) error {
+	h.mu.Lock()
+	hooks := append([]Hook(nil), h.beforeCommit...)
+	h.mu.Unlock()
+
+	return runHooks(ctx, hooks)
+}
+
+// runOnCommit runs the registered post-commit hooks, in registration order,
+// stopping at the first error.
+func (h *hookSet) runOnCommit(ctx context.Context) error {
+	h.mu.Lock()
+	hooks := append([]Hook(nil), h.onCommit...)
+	h.mu.Unlock()
+
+	return runHooks(ctx, hooks)
+}
+
+// runOnRollback runs the registered rollback hooks, in registration order,
+// stopping at the first error.
+func (h *hookSet) runOnRollback(ctx context.Context) error {
+	h.mu.Lock()
+	hooks := append([]Hook(nil), h.onRollback...)
+	h.mu.Unlock()
+
+	return runHooks(ctx, hooks)
+}
+
+// mergeInto appends h's hooks to parent's, promoting them to run at
+// parent's own commit or rollback instead of h's. doOnce calls this when a
+// nested Do's own savepoint release succeeds, since only the outermost Do's
+// commit is durable; a nested Do whose own savepoint fails instead fires and
+// discards h without merging it.
+func (h *hookSet) mergeInto(parent *hookSet) {
+	h.mu.Lock()
+	beforeCommit := append([]Hook(nil), h.beforeCommit...)
+	onCommit := append([]Hook(nil), h.onCommit...)
+	onRollback := append([]Hook(nil), h.onRollback...)
+	h.mu.Unlock()
+
+	parent.mu.Lock()
+	defer parent.mu.Unlock()
+	parent.beforeCommit = append(parent.beforeCommit, beforeCommit...)
+	parent.onCommit = append(parent.onCommit, onCommit...)
+	parent.onRollback = append(parent.onRollback, onRollback...)
+}
+
+func runHooks(ctx context.Context, hooks []Hook) error {
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
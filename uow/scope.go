@@ -0,0 +1,85 @@
+package uow
+
+import "context"
+
+// TenantID identifies the tenant a DoAs transaction runs as.
+type TenantID string
+
+// Scope carries the tenant a transaction runs as, plus any extra key/value
+// data set with WithScope, to a ScopedRepositoryFactory or TX.Scope().
+//
+// The zero value is a Scope with no tenant and no values, returned by
+// TX.Scope() for transactions started through Do outside any DoAs and
+// without WithScope.
+type Scope struct {
+	tenantID TenantID
+	values   map[string]any
+}
+
+// TenantID returns the tenant this Scope runs as, empty if it was never set
+// through DoAs.
+func (s Scope) TenantID() TenantID {
+	return s.tenantID
+}
+
+// Value returns the scope data set under key through WithScope, if any.
+func (s Scope) Value(key string) (any, bool) {
+	v, ok := s.values[key]
+	return v, ok
+}
+
+// withTenant returns a copy of s with its tenant id set to id.
+func (s Scope) withTenant(id TenantID) Scope {
+	s.tenantID = id
+	return s
+}
+
+// withValue returns a copy of s with key set to value.
+func (s Scope) withValue(key string, value any) Scope {
+	values := make(map[string]any, len(s.values)+1)
+	for k, v := range s.values {
+		values[k] = v
+	}
+	values[key] = value
+
+	s.values = values
+	return s
+}
+
+type scopeCtxKey struct{}
+
+// withScope returns a copy of ctx carrying scope as the ambient Scope, read
+// back by scopeFromCtx when a later Do or DoAs call resolves its own Scope.
+func withScope(ctx context.Context, scope Scope) context.Context {
+	return context.WithValue(ctx, scopeCtxKey{}, scope)
+}
+
+// scopeFromCtx returns the ambient Scope carried by ctx, set by an outer
+// DoAs or Do call, if any.
+func scopeFromCtx(ctx context.Context) (Scope, bool) {
+	scope, ok := ctx.Value(scopeCtxKey{}).(Scope)
+	return scope, ok
+}
+
+// resolveScope builds the Scope for a Do/DoAs call: it starts from whatever
+// Scope is already ambient on ctx, so a Do nested inside a DoAs inherits its
+// tenant, then layers on the key/value pairs set through WithScope. If any
+// values were added, it returns a ctx carrying the combined Scope so that
+// further nested Do calls see it too.
+func resolveScope(ctx context.Context, values []scopeValue) (context.Context, Scope) {
+	scope, _ := scopeFromCtx(ctx)
+	if len(values) == 0 {
+		return ctx, scope
+	}
+
+	for _, v := range values {
+		scope = scope.withValue(v.key, v.value)
+	}
+
+	return withScope(ctx, scope), scope
+}
+
+type scopeValue struct {
+	key   string
+	value any
+}
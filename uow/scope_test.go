@@ -0,0 +1,55 @@
+package uow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Scope_ZeroValue(t *testing.T) {
+	var s Scope
+	assert.Equal(t, TenantID(""), s.TenantID())
+
+	_, ok := s.Value("region")
+	assert.False(t, ok)
+}
+
+func Test_Scope_WithTenant(t *testing.T) {
+	s := Scope{}.withTenant("tenant-1")
+	assert.Equal(t, TenantID("tenant-1"), s.TenantID())
+}
+
+func Test_Scope_WithValue(t *testing.T) {
+	s := Scope{}.withValue("region", "us-east")
+
+	v, ok := s.Value("region")
+	assert.True(t, ok)
+	assert.Equal(t, "us-east", v)
+
+	_, ok = s.Value("missing")
+	assert.False(t, ok)
+}
+
+func Test_ResolveScope_WhenNoAmbientScope(t *testing.T) {
+	ctx, scope := resolveScope(context.Background(), nil)
+	assert.Equal(t, TenantID(""), scope.TenantID())
+
+	_, ok := scopeFromCtx(ctx)
+	assert.False(t, ok)
+}
+
+func Test_ResolveScope_InheritsAmbientScopeAndAddsValues(t *testing.T) {
+	ctx := withScope(context.Background(), Scope{}.withTenant("tenant-1"))
+
+	ctx, scope := resolveScope(ctx, []scopeValue{{key: "region", value: "us-east"}})
+	assert.Equal(t, TenantID("tenant-1"), scope.TenantID())
+
+	v, ok := scope.Value("region")
+	assert.True(t, ok)
+	assert.Equal(t, "us-east", v)
+
+	ambient, ok := scopeFromCtx(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, scope, ambient)
+}
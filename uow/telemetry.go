@@ -0,0 +1,220 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/sesaquecruz/go-unit-of-work/uow"
+
+// defaultDBSystem is the db.system span attribute reported for a Backend
+// that doesn't implement DBSystem.
+const defaultDBSystem = "other_sql"
+
+// dbSystemOf reports backend's OpenTelemetry db.system value; see DBSystem.
+func dbSystemOf(backend Backend) string {
+	if s, ok := backend.(DBSystem); ok {
+		return s.DBSystem()
+	}
+
+	return defaultDBSystem
+}
+
+// UOWOption configures a UnitOfWork's telemetry. See WithTracerProvider and
+// WithMetrics. Neither is required: with no UOWOption, Do runs without
+// tracing or metrics overhead.
+type UOWOption func(*telemetry)
+
+// WithTracerProvider makes Do wrap each call in an OTel span named uow.Do.
+func WithTracerProvider(tp trace.TracerProvider) UOWOption {
+	return func(t *telemetry) {
+		t.tracer = tp.Tracer(instrumentationName)
+	}
+}
+
+// WithMetrics registers Prometheus collectors for transaction duration,
+// commit/rollback counts, and retry counts with reg, all labeled by the name
+// given through WithName.
+func WithMetrics(reg prometheus.Registerer) UOWOption {
+	return func(t *telemetry) {
+		t.metrics = newMetricsCollector(reg)
+	}
+}
+
+// telemetry holds a UnitOfWork's optional tracer and metrics. The zero
+// value, produced when no UOWOption is given, traces with a no-op tracer
+// and skips metrics entirely, so Do pays nothing by default.
+type telemetry struct {
+	tracer  trace.Tracer
+	metrics *metricsCollector
+}
+
+func newTelemetry(opts []UOWOption) *telemetry {
+	t := &telemetry{tracer: trace.NewNoopTracerProvider().Tracer(instrumentationName)}
+	for _, opt := range opts {
+		opt(t)
+	}
+
+	return t
+}
+
+// startSpan starts the uow.Do span for a Do call named name (see WithName),
+// empty if the caller did not give one, running dbSystem's Backend at
+// isolation.
+func (t *telemetry) startSpan(ctx context.Context, name string, dbSystem string, isolation sql.IsolationLevel) (context.Context, trace.Span) {
+	ctx, span := t.tracer.Start(ctx, "uow.Do", trace.WithAttributes(
+		attribute.String("db.system", dbSystem),
+		attribute.String("uow.isolation", isolation.String()),
+	))
+
+	if name != "" {
+		span.SetAttributes(attribute.String("uow.name", name))
+	}
+
+	return ctx, span
+}
+
+// recordRetry counts a Do retry for name.
+func (t *telemetry) recordRetry(name string) {
+	if t.metrics != nil {
+		t.metrics.retries.WithLabelValues(name).Inc()
+	}
+}
+
+// recordOutcome records a Do call's duration and outcome ("committed" or
+// "rolled_back") for name.
+func (t *telemetry) recordOutcome(name, outcome string, duration time.Duration) {
+	if t.metrics == nil {
+		return
+	}
+
+	t.metrics.duration.WithLabelValues(name).Observe(duration.Seconds())
+	switch outcome {
+	case "committed":
+		t.metrics.commits.WithLabelValues(name).Inc()
+	case "rolled_back":
+		t.metrics.rollbacks.WithLabelValues(name).Inc()
+	}
+}
+
+// metricsCollector is the set of Prometheus collectors registered by
+// WithMetrics, all labeled by the Do call's name (see WithName).
+type metricsCollector struct {
+	duration  *prometheus.HistogramVec
+	commits   *prometheus.CounterVec
+	rollbacks *prometheus.CounterVec
+	retries   *prometheus.CounterVec
+}
+
+func newMetricsCollector(reg prometheus.Registerer) *metricsCollector {
+	m := &metricsCollector{
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name: "uow_do_duration_seconds",
+			Help: "Duration of UnitOfWork Do calls, including retries.",
+		}, []string{"name"}),
+		commits: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "uow_do_commits_total",
+			Help: "Number of Do calls that committed.",
+		}, []string{"name"}),
+		rollbacks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "uow_do_rollbacks_total",
+			Help: "Number of Do calls that rolled back.",
+		}, []string{"name"}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "uow_do_retries_total",
+			Help: "Number of times Do retried a failed transaction.",
+		}, []string{"name"}),
+	}
+
+	reg.MustRegister(m.duration, m.commits, m.rollbacks, m.retries)
+	return m
+}
+
+// recordingTX wraps the TX given to fn so Do can read back, after fn
+// returns, which repositories it resolved through Get and attach them to
+// the uow.Do span as the uow.repositories attribute.
+type recordingTX struct {
+	mu    sync.Mutex
+	tx    TX
+	names []string
+}
+
+func newRecordingTX() *recordingTX {
+	return &recordingTX{}
+}
+
+// bind points the recorder at the TX for the current attempt, discarding
+// names recorded by any previous attempt. doOnce calls this once per
+// attempt, since Do reuses the same recorder across retries, and without
+// the reset a repository resolved on every attempt would be reported once
+// per retry instead of once.
+func (r *recordingTX) bind(tx TX) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tx = tx
+	r.names = nil
+}
+
+func (r *recordingTX) Get(name RepositoryName) (Repository, error) {
+	r.mu.Lock()
+	r.names = append(r.names, string(name))
+	tx := r.tx
+	r.mu.Unlock()
+
+	return tx.Get(name)
+}
+
+func (r *recordingTX) OnBeforeCommit(hook Hook) {
+	r.mu.Lock()
+	tx := r.tx
+	r.mu.Unlock()
+	tx.OnBeforeCommit(hook)
+}
+
+func (r *recordingTX) OnCommit(hook Hook) {
+	r.mu.Lock()
+	tx := r.tx
+	r.mu.Unlock()
+	tx.OnCommit(hook)
+}
+
+func (r *recordingTX) OnRollback(hook Hook) {
+	r.mu.Lock()
+	tx := r.tx
+	r.mu.Unlock()
+	tx.OnRollback(hook)
+}
+
+func (r *recordingTX) Scope() Scope {
+	r.mu.Lock()
+	tx := r.tx
+	r.mu.Unlock()
+	return tx.Scope()
+}
+
+func (r *recordingTX) recordedNames() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string(nil), r.names...)
+}
+
+// recordSpanOutcome attaches the repositories fn resolved through rec, the
+// Do call's outcome, and how many times it retried to span, recording err
+// if the outcome is "rolled_back".
+func recordSpanOutcome(span trace.Span, rec *recordingTX, outcome string, retries int, err error) {
+	span.SetAttributes(
+		attribute.StringSlice("uow.repositories", rec.recordedNames()),
+		attribute.String("uow.outcome", outcome),
+		attribute.Int("uow.retries", retries),
+	)
+
+	if err != nil {
+		span.RecordError(err)
+	}
+}
@@ -0,0 +1,290 @@
+package uow
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func Test_UnitOfWork_WithBackend_Do_WithoutTelemetry_IsNoop(t *testing.T) {
+	uow := NewUnitOfWorkWithBackend(&fakeBackend{})
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithTracerProvider_RecordsSpan(t *testing.T) {
+	backend := &fakeBackend{}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	uow := NewUnitOfWorkWithBackend(backend, WithTracerProvider(tp))
+
+	uow.Register("FakeRepository", func(handle any) Repository {
+		return &fakeRepository{handle: handle}
+	})
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		_, err := tx.Get("FakeRepository")
+		return err
+	}, WithName("checkout"))
+	require.Nil(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+
+	span := spans[0]
+	assert.Equal(t, "uow.Do", span.Name)
+
+	attrs := make(map[string]string)
+	for _, a := range span.Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	assert.Equal(t, "checkout", attrs["uow.name"])
+	assert.Equal(t, "committed", attrs["uow.outcome"])
+	assert.Equal(t, defaultDBSystem, attrs["db.system"])
+	assert.Equal(t, "Default", attrs["uow.isolation"])
+	assert.Equal(t, "0", attrs["uow.retries"])
+	assert.Contains(t, attrs["uow.repositories"], "FakeRepository")
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithTracerProvider_RecordsIsolation(t *testing.T) {
+	backend := &fakeBackend{}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	uow := NewUnitOfWorkWithBackend(backend, WithTracerProvider(tp))
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return nil
+	}, WithIsolation(sql.LevelSerializable))
+	require.Nil(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+
+	attrs := make(map[string]string)
+	for _, a := range spans[0].Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	assert.Equal(t, sql.LevelSerializable.String(), attrs["uow.isolation"])
+}
+
+func Test_UnitOfWork_WithBackend_Do_NestedDo_RecordsOuterIsolation(t *testing.T) {
+	backend := &fakeBackend{}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	uow := NewUnitOfWorkWithBackend(backend, WithTracerProvider(tp))
+
+	// fakeBackendTx doesn't implement SavepointTx, so this nested Do fails
+	// with ErrNestedDoNotSupported, but its span attributes should still
+	// reflect the outer transaction's isolation, not its own ignored option.
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return uow.Do(ctx, func(ctx context.Context, tx TX) error {
+			return nil
+		}, WithIsolation(sql.LevelSerializable))
+	}, WithIsolation(sql.LevelReadCommitted))
+
+	assert.ErrorIs(t, err, ErrNestedDoNotSupported)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 2)
+
+	innerAttrs := make(map[string]string)
+	for _, a := range spans[0].Attributes {
+		innerAttrs[string(a.Key)] = a.Value.Emit()
+	}
+	assert.Equal(t, sql.LevelReadCommitted.String(), innerAttrs["uow.isolation"])
+}
+
+// fakeDBSystemBackend reports a db.system distinct from defaultDBSystem, so
+// Do's span attribute can be told apart from the fallback.
+type fakeDBSystemBackend struct {
+	fakeBackend
+}
+
+func (b *fakeDBSystemBackend) DBSystem() string {
+	return "fakedb"
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithTracerProvider_RecordsBackendDBSystem(t *testing.T) {
+	backend := &fakeDBSystemBackend{}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	uow := NewUnitOfWorkWithBackend(backend, WithTracerProvider(tp))
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return nil
+	})
+	require.Nil(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+
+	attrs := make(map[string]string)
+	for _, a := range spans[0].Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	assert.Equal(t, "fakedb", attrs["db.system"])
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithTracerProvider_RecordsRetryCountOnSpan(t *testing.T) {
+	backend := &fakeBackend{}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	uow := NewUnitOfWorkWithBackend(backend, WithTracerProvider(tp))
+
+	attempts := 0
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("Error 1213: Deadlock found when trying to get lock")
+		}
+		return nil
+	}, WithRetry(5, func(attempt int) time.Duration { return 0 }))
+	require.Nil(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+
+	attrs := make(map[string]string)
+	for _, a := range spans[0].Attributes {
+		attrs[string(a.Key)] = a.Value.Emit()
+	}
+	assert.Equal(t, "2", attrs["uow.retries"])
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithTracerProvider_RetryDoesNotDuplicateRepositories(t *testing.T) {
+	backend := &fakeBackend{}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	uow := NewUnitOfWorkWithBackend(backend, WithTracerProvider(tp))
+
+	uow.Register("FakeRepository", func(handle any) Repository {
+		return &fakeRepository{handle: handle}
+	})
+
+	attempts := 0
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		attempts++
+		if _, err := tx.Get("FakeRepository"); err != nil {
+			return err
+		}
+		if attempts < 3 {
+			return errors.New("Error 1213: Deadlock found when trying to get lock")
+		}
+		return nil
+	}, WithRetry(5, func(attempt int) time.Duration { return 0 }))
+	require.Nil(t, err)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+
+	var repositories []string
+	for _, a := range spans[0].Attributes {
+		if string(a.Key) == "uow.repositories" {
+			repositories = a.Value.AsStringSlice()
+		}
+	}
+
+	assert.Equal(t, []string{"FakeRepository"}, repositories)
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithTracerProvider_WhenFnFailsRecordsError(t *testing.T) {
+	backend := &fakeBackend{}
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+
+	uow := NewUnitOfWorkWithBackend(backend, WithTracerProvider(tp))
+
+	fnErr := errors.New("some error")
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return fnErr
+	})
+	assert.ErrorIs(t, err, fnErr)
+
+	spans := exporter.GetSpans()
+	require.Len(t, spans, 1)
+	require.Len(t, spans[0].Events, 1)
+	assert.Equal(t, "exception", spans[0].Events[0].Name)
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithMetrics_RecordsCommitAndDuration(t *testing.T) {
+	backend := &fakeBackend{}
+	reg := prometheus.NewRegistry()
+
+	uow := NewUnitOfWorkWithBackend(backend, WithMetrics(reg))
+
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		return nil
+	}, WithName("checkout"))
+	assert.Nil(t, err)
+
+	metrics, err := reg.Gather()
+	require.Nil(t, err)
+
+	var commits, duration *dto.MetricFamily
+	for _, m := range metrics {
+		switch m.GetName() {
+		case "uow_do_commits_total":
+			commits = m
+		case "uow_do_duration_seconds":
+			duration = m
+		}
+	}
+
+	require.NotNil(t, commits)
+	require.Len(t, commits.Metric, 1)
+	assert.Equal(t, float64(1), commits.Metric[0].GetCounter().GetValue())
+	assert.Equal(t, "checkout", commits.Metric[0].GetLabel()[0].GetValue())
+
+	require.NotNil(t, duration)
+	require.Len(t, duration.Metric, 1)
+	assert.Equal(t, uint64(1), duration.Metric[0].GetHistogram().GetSampleCount())
+}
+
+func Test_UnitOfWork_WithBackend_Do_WithMetrics_RecordsRetry(t *testing.T) {
+	backend := &fakeBackend{}
+	reg := prometheus.NewRegistry()
+
+	uow := NewUnitOfWorkWithBackend(backend, WithMetrics(reg))
+
+	attempts := 0
+	err := uow.Do(context.Background(), func(ctx context.Context, tx TX) error {
+		attempts++
+		if attempts < 2 {
+			return errors.New("Error 1213: Deadlock found when trying to get lock")
+		}
+		return nil
+	}, WithRetry(5, func(attempt int) time.Duration { return 0 }))
+	assert.Nil(t, err)
+
+	metrics, err := reg.Gather()
+	require.Nil(t, err)
+
+	var retries *dto.MetricFamily
+	for _, m := range metrics {
+		if m.GetName() == "uow_do_retries_total" {
+			retries = m
+		}
+	}
+
+	require.NotNil(t, retries)
+	require.Len(t, retries.Metric, 1)
+	assert.Equal(t, float64(1), retries.Metric[0].GetCounter().GetValue())
+}
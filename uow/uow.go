@@ -2,27 +2,392 @@
 //
 // The Unit of Work allows you to encapsulate a set of database operations within a single transaction.
 // This package contains an interface and implementation of a Unit of Work.
+//
+// Besides the factory-based TX.Get API, the active *sql.Tx is also carried on
+// the ctx passed to Do, so repositories can instead resolve their connection
+// with Conn(ctx, db) and be shared across Do calls without registration.
+//
+// DoAs runs Do as a given tenant, making that tenant available to
+// repositories registered with RegisterScoped through TX.Scope().
+//
+// UnitOfWork runs its transactions through a Backend, decoupling it from any
+// specific driver. NewUnitOfWork targets database/sql directly, the default
+// Backend; NewUnitOfWorkWithBackend targets any other driver, see the
+// uow/backend subpackages.
 package uow
 
 import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
 )
 
 var (
 	ErrRepositoryNotRegistered     = errors.New("repository not registered")
 	ErrRepositoryAlreadyRegistered = errors.New("repository already registered")
 	ErrInvalidRepositoryType       = errors.New("invalid repository type")
+	ErrNestedDoNotSupported        = errors.New("backend does not support nested Do")
 )
 
 type RepositoryName string
 type Repository any
-type RepositoryFactory func(tx *sql.Tx) Repository
+
+// RepositoryFactory builds a repository from the opaque handle of the
+// transaction a Do call is running, e.g. a *sql.Tx, pgx.Tx or *gorm.DB,
+// depending on the Backend in use.
+type RepositoryFactory func(handle any) Repository
+
+// ScopedRepositoryFactory is the tenant-aware counterpart of
+// RepositoryFactory, registered with RegisterScoped. Transaction.Get passes
+// it the Scope resolved for the current Do/DoAs call, so the repository can
+// scope its queries (e.g. by tenant id) without every call site remembering
+// to pass it. See DoAs and WithScope.
+type ScopedRepositoryFactory func(handle any, scope Scope) Repository
+
+// sqldb is the subset of *sql.DB and *sql.Tx used to run queries. It lets a
+// repository hold just a *sql.DB and still run inside whatever transaction,
+// if any, is active on the ctx it is given.
+type sqldb interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// sqlTx adapts a *sql.Tx as a BackendTx that also supports SAVEPOINT-based
+// nesting. It backs both the package-level Do/WithTx helpers and dbBackend,
+// the default Backend NewUnitOfWork builds from a *sql.DB.
+type sqlTx struct {
+	tx *sql.Tx
+}
+
+func (t *sqlTx) Commit() error   { return t.tx.Commit() }
+func (t *sqlTx) Rollback() error { return t.tx.Rollback() }
+func (t *sqlTx) Handle() any     { return t.tx }
+
+func (t *sqlTx) Savepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "SAVEPOINT "+name)
+	return err
+}
+
+func (t *sqlTx) ReleaseSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name)
+	return err
+}
+
+func (t *sqlTx) RollbackToSavepoint(ctx context.Context, name string) error {
+	_, err := t.tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+	return err
+}
+
+// dbBackend adapts a *sql.DB as the default Backend, the one NewUnitOfWork
+// and the package-level Do/WithTx helpers use.
+type dbBackend struct {
+	db *sql.DB
+}
+
+func (b *dbBackend) Begin(ctx context.Context, opts *sql.TxOptions) (BackendTx, error) {
+	tx, err := b.db.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	return &sqlTx{tx: tx}, nil
+}
+
+type ctxKey struct{}
+
+// txHandle is the ambient transaction stored in a ctx. savepoints counts the
+// savepoints opened on btx so far, so that nested Do calls sharing the same
+// ctx chain pick unique savepoint names; it is shared by pointer across
+// those nested calls. hooks is the hookSet currently registering hooks for
+// whichever Do call is innermost at the moment; see doOnce. isolation is the
+// level btx actually began at, so a nested Do can report it instead of its
+// own ignored isolation option; see UnitOfWork.Do.
+type txHandle struct {
+	btx        BackendTx
+	savepoints int32
+	hooks      *hookSet
+	isolation  sql.IsolationLevel
+}
+
+// nextSavepoint returns a unique, unused savepoint name for h.btx.
+func (h *txHandle) nextSavepoint() string {
+	n := atomic.AddInt32(&h.savepoints, 1)
+	return fmt.Sprintf("sp_%d", n)
+}
+
+// withTx returns a copy of ctx carrying btx, begun at isolation, as its
+// ambient transaction.
+func withTx(ctx context.Context, btx BackendTx, isolation sql.IsolationLevel) context.Context {
+	return context.WithValue(ctx, ctxKey{}, &txHandle{btx: btx, hooks: newHookSet(), isolation: isolation})
+}
+
+// txHandleFromCtx returns the ambient transaction handle stored in ctx, if any.
+func txHandleFromCtx(ctx context.Context) (*txHandle, bool) {
+	h, ok := ctx.Value(ctxKey{}).(*txHandle)
+	return h, ok
+}
+
+// txFromCtx returns the ambient *sql.Tx stored in ctx, if any. It reports
+// false both when ctx carries no transaction and when it carries one from a
+// Backend other than the default *sql.DB-backed one.
+func txFromCtx(ctx context.Context) (*sql.Tx, bool) {
+	h, ok := txHandleFromCtx(ctx)
+	if !ok {
+		return nil, false
+	}
+
+	tx, ok := h.btx.Handle().(*sql.Tx)
+	return tx, ok
+}
+
+// Conn returns the ambient *sql.Tx carried by ctx, set by Do or WithTx.
+// If ctx carries no transaction, it falls back to db.
+//
+// It lets a repository be constructed once at startup holding only db, and
+// resolve the right connection from ctx on every method call, instead of
+// being looked up through TX.Get on every Do call.
+func Conn(ctx context.Context, db *sql.DB) sqldb {
+	if tx, ok := txFromCtx(ctx); ok {
+		return tx
+	}
+
+	return db
+}
+
+// WithTx begins a transaction on db and returns a ctx carrying it, along
+// with commit and rollback functions the caller is responsible for calling
+// exactly one of.
+//
+// It is the low-level building block behind Do, for callers that need to
+// manage the transaction lifecycle themselves, for example to nest further
+// business logic before deciding to commit. Like Do, if ctx already carries
+// a transaction, WithTx opens a savepoint on it instead of a new one; see
+// beginOrNest.
+func WithTx(ctx context.Context, db *sql.DB) (context.Context, func() error, func() error) {
+	txCtx, commit, rollback, err := beginOrNest(ctx, &dbBackend{db: db}, nil)
+	if err != nil {
+		fail := func() error { return err }
+		return ctx, fail, fail
+	}
+
+	return txCtx, commit, rollback
+}
+
+// beginOrNest starts a transaction on backend with txOpts, unless ctx already
+// carries one, in which case it opens a savepoint on it instead (txOpts is
+// then ignored, since a savepoint inherits its transaction's settings) —
+// provided the active transaction's BackendTx implements SavepointTx. If it
+// doesn't, nesting fails with ErrNestedDoNotSupported.
+//
+// Either way it returns a ctx carrying the transaction and commit/rollback
+// functions that release or roll back just that savepoint, or the whole
+// transaction at the top level.
+//
+// This is what lets Do be called from within a fn already running inside
+// another Do: the outer Do still owns the final commit/rollback, while an
+// inner failure only undoes the inner savepoint's work.
+func beginOrNest(ctx context.Context, backend Backend, txOpts *sql.TxOptions) (context.Context, func() error, func() error, error) {
+	if handle, ok := txHandleFromCtx(ctx); ok {
+		sp, ok := handle.btx.(SavepointTx)
+		if !ok {
+			return ctx, nil, nil, ErrNestedDoNotSupported
+		}
+
+		name := handle.nextSavepoint()
+		if err := sp.Savepoint(ctx, name); err != nil {
+			return ctx, nil, nil, err
+		}
+
+		commit := func() error { return sp.ReleaseSavepoint(ctx, name) }
+		rollback := func() error { return sp.RollbackToSavepoint(ctx, name) }
+
+		return ctx, commit, rollback, nil
+	}
+
+	btx, err := backend.Begin(ctx, txOpts)
+	if err != nil {
+		return ctx, nil, nil, err
+	}
+
+	isolation := sql.LevelDefault
+	if txOpts != nil {
+		isolation = txOpts.Isolation
+	}
+
+	return withTx(ctx, btx, isolation), btx.Commit, btx.Rollback, nil
+}
+
+// doOptions holds the settings assembled from a Do call's Option list.
+type doOptions struct {
+	txOpts      *sql.TxOptions
+	retries     int
+	backoff     func(attempt int) time.Duration
+	name        string
+	scopeValues []scopeValue
+}
+
+// Option configures a Do call. See WithIsolation, WithReadOnly, WithRetry,
+// WithName and WithScope.
+type Option func(*doOptions)
+
+func newDoOptions(opts []Option) *doOptions {
+	options := &doOptions{}
+	for _, opt := range opts {
+		opt(options)
+	}
+
+	return options
+}
+
+// WithIsolation sets the isolation level used to begin the transaction.
+// It has no effect when Do nests inside an already active transaction,
+// which always runs at its outer transaction's isolation level.
+func WithIsolation(level sql.IsolationLevel) Option {
+	return func(o *doOptions) {
+		if o.txOpts == nil {
+			o.txOpts = &sql.TxOptions{}
+		}
+		o.txOpts.Isolation = level
+	}
+}
+
+// WithReadOnly marks the transaction as read-only. It has no effect when Do
+// nests inside an already active transaction.
+func WithReadOnly(readOnly bool) Option {
+	return func(o *doOptions) {
+		if o.txOpts == nil {
+			o.txOpts = &sql.TxOptions{}
+		}
+		o.txOpts.ReadOnly = readOnly
+	}
+}
+
+// WithRetry makes Do retry fn, with a fresh transaction, up to n more times
+// when it fails with a Postgres serialization failure (SQLSTATE 40001) or a
+// MySQL deadlock (Error 1213). backoff is called with the attempt number
+// (starting at 0) before each retry to determine how long to wait.
+func WithRetry(n int, backoff func(attempt int) time.Duration) Option {
+	return func(o *doOptions) {
+		o.retries = n
+		o.backoff = backoff
+	}
+}
+
+// WithName labels a Do call with a business flow name, e.g. "checkout", so
+// the uow.Do span and the metrics recorded by WithMetrics can be attributed
+// to it. Without it, the span carries no uow.name attribute and metrics are
+// recorded under an empty name label.
+func WithName(name string) Option {
+	return func(o *doOptions) {
+		o.name = name
+	}
+}
+
+// WithScope adds a key/value pair to the Scope seen by this call's
+// ScopedRepositoryFactory and TX.Scope(), in addition to whatever tenant id
+// DoAs (or an outer DoAs this Do is nested in) already set on it.
+func WithScope(key string, value any) Option {
+	return func(o *doOptions) {
+		o.scopeValues = append(o.scopeValues, scopeValue{key: key, value: value})
+	}
+}
+
+// isRetryable reports whether err looks like a Postgres serialization
+// failure or a MySQL deadlock, both of which are expected to succeed if the
+// transaction is simply retried.
+func isRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "SQLSTATE 40001") || strings.Contains(msg, "Error 1213")
+}
+
+// Do begins a transaction on db, seeds ctx so that Conn(ctx, db) resolves to
+// it, and calls fn. If fn returns an error, the transaction is rolled back
+// and the error is returned. Otherwise, the transaction is committed.
+//
+// If ctx already carries a transaction, because Do is called from within
+// another Do's fn, Do opens a savepoint on it instead of a new transaction;
+// see beginOrNest.
+//
+// opts configures the transaction; see WithIsolation, WithReadOnly and
+// WithRetry.
+//
+// Do is a package-level convenience for composing business logic and domain
+// services around Conn, without registering repositories with a UnitOfWork.
+func Do(ctx context.Context, db *sql.DB, fn func(ctx context.Context) error, opts ...Option) error {
+	options := newDoOptions(opts)
+	backend := &dbBackend{db: db}
+
+	for attempt := 0; ; attempt++ {
+		err := doOnce(ctx, backend, fn, options.txOpts)
+		if err == nil || attempt >= options.retries || !isRetryable(err) {
+			return err
+		}
+
+		if options.backoff != nil {
+			time.Sleep(options.backoff(attempt))
+		}
+	}
+}
+
+func doOnce(ctx context.Context, backend Backend, fn func(ctx context.Context) error, txOpts *sql.TxOptions) error {
+	txCtx, commit, rollback, err := beginOrNest(ctx, backend, txOpts)
+	if err != nil {
+		return err
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			rollback()
+		}
+	}()
+
+	if err := fn(txCtx); err != nil {
+		return err
+	}
+
+	if err := commit(); err != nil {
+		return err
+	}
+	committed = true
+
+	return nil
+}
 
 // Transaction interface.
 type TX interface {
 	Get(name RepositoryName) (Repository, error)
+
+	// OnBeforeCommit registers a hook that runs inside the transaction,
+	// right before it commits. If it returns an error, the commit is
+	// aborted and the transaction is rolled back instead.
+	OnBeforeCommit(hook Hook)
+
+	// OnCommit registers a hook that runs after the transaction has
+	// successfully committed, outside of it. This is the primitive for
+	// dispatching domain events or outbox rows only once the work they
+	// describe is durable.
+	OnCommit(hook Hook)
+
+	// OnRollback registers a hook that runs after the transaction has been
+	// rolled back.
+	OnRollback(hook Hook)
+
+	// Scope returns the Scope resolved for this transaction: the tenant id
+	// set by DoAs, if any, plus any key/value data added with WithScope. A
+	// transaction started through Do, outside any DoAs and without
+	// WithScope, has the zero Scope.
+	Scope() Scope
 }
 
 // Unit of Work interface.
@@ -31,20 +396,39 @@ type UOW interface {
 	Remove(name RepositoryName) error
 	Has(name RepositoryName) bool
 	Clear()
-	Do(ctx context.Context, fn func(ctx context.Context, tx TX) error) error
+	Do(ctx context.Context, fn func(ctx context.Context, tx TX) error, opts ...Option) error
 }
 
 // Transaction implementation.
 type Transaction struct {
-	tx           *sql.Tx
-	repositories map[RepositoryName]RepositoryFactory
+	handle             any
+	repositories       map[RepositoryName]RepositoryFactory
+	scopedRepositories map[RepositoryName]ScopedRepositoryFactory
+	hooks              *hookSet
+	scope              Scope
 }
 
 // Create a new transaction. Return a pointer to a transaction.
-func NewTransaction(tx *sql.Tx, repositories map[RepositoryName]RepositoryFactory) *Transaction {
+func NewTransaction(handle any, repositories map[RepositoryName]RepositoryFactory) *Transaction {
+	return newTransaction(handle, repositories, nil, newHookSet(), Scope{})
+}
+
+// newTransaction is used internally by Do, so that each Do call, nested or
+// not, registers its hooks on the hookSet doOnce gives its own level, and
+// sees the Scope resolved for this Do/DoAs call.
+func newTransaction(
+	handle any,
+	repositories map[RepositoryName]RepositoryFactory,
+	scopedRepositories map[RepositoryName]ScopedRepositoryFactory,
+	hooks *hookSet,
+	scope Scope,
+) *Transaction {
 	return &Transaction{
-		tx:           tx,
-		repositories: repositories,
+		handle:             handle,
+		repositories:       repositories,
+		scopedRepositories: scopedRepositories,
+		hooks:              hooks,
+		scope:              scope,
 	}
 }
 
@@ -66,31 +450,78 @@ func GetAs[T any](t TX, name RepositoryName) (T, error) {
 
 // Given a repository name returns a repository. Return an error if the repository does not exist.
 func (t *Transaction) Get(name RepositoryName) (Repository, error) {
+	if factory, ok := t.scopedRepositories[name]; ok {
+		return factory(t.handle, t.scope), nil
+	}
+
 	if repository, ok := t.repositories[name]; ok {
-		return repository(t.tx), nil
+		return repository(t.handle), nil
 	}
 
 	return nil, ErrRepositoryNotRegistered
 }
 
-// Unit of Work implementation
+// Scope returns the Scope resolved for this transaction.
+func (t *Transaction) Scope() Scope {
+	return t.scope
+}
+
+// OnBeforeCommit registers a hook that runs inside the transaction, right
+// before it commits.
+func (t *Transaction) OnBeforeCommit(hook Hook) {
+	t.hooks.addBeforeCommit(hook)
+}
+
+// OnCommit registers a hook that runs after the transaction has
+// successfully committed, outside of it.
+func (t *Transaction) OnCommit(hook Hook) {
+	t.hooks.addOnCommit(hook)
+}
+
+// OnRollback registers a hook that runs after the transaction has been
+// rolled back.
+func (t *Transaction) OnRollback(hook Hook) {
+	t.hooks.addOnRollback(hook)
+}
+
+// Unit of Work implementation. It runs its transactions through a Backend,
+// so it works the same way regardless of which driver that Backend wraps.
 type UnitOfWork struct {
-	db           *sql.DB
-	repositories map[RepositoryName]RepositoryFactory
+	backend            Backend
+	repositories       map[RepositoryName]RepositoryFactory
+	scopedRepositories map[RepositoryName]ScopedRepositoryFactory
+	telemetry          *telemetry
 }
 
-// Create a new unit of work. Return a pointer to a unit of work.
-func NewUnitOfWork(db *sql.DB) *UnitOfWork {
+// Create a new unit of work that runs its transactions on db via
+// database/sql, the default Backend. Use NewUnitOfWorkWithBackend to target
+// a different driver instead; see the uow/backend subpackages.
+//
+// opts wires in tracing and/or metrics; see WithTracerProvider and
+// WithMetrics. With neither, Do runs without tracing or metrics overhead.
+func NewUnitOfWork(db *sql.DB, opts ...UOWOption) *UnitOfWork {
+	return NewUnitOfWorkWithBackend(&dbBackend{db: db}, opts...)
+}
+
+// NewUnitOfWorkWithBackend creates a unit of work that runs its
+// transactions through backend. Only a backend whose BackendTx also
+// implements SavepointTx supports nested Do calls; see Backend.
+//
+// opts wires in tracing and/or metrics; see WithTracerProvider and
+// WithMetrics. With neither, Do runs without tracing or metrics overhead.
+func NewUnitOfWorkWithBackend(backend Backend, opts ...UOWOption) *UnitOfWork {
 	return &UnitOfWork{
-		db:           db,
-		repositories: make(map[RepositoryName]RepositoryFactory),
+		backend:            backend,
+		repositories:       make(map[RepositoryName]RepositoryFactory),
+		scopedRepositories: make(map[RepositoryName]ScopedRepositoryFactory),
+		telemetry:          newTelemetry(opts),
 	}
 }
 
 // Register a repository factory with the given repository name.
 // Return an error if a repository name already registered.
 func (u *UnitOfWork) Register(name RepositoryName, factory RepositoryFactory) error {
-	if _, ok := u.repositories[name]; ok {
+	if u.Has(name) {
 		return ErrRepositoryAlreadyRegistered
 	}
 
@@ -98,27 +529,45 @@ func (u *UnitOfWork) Register(name RepositoryName, factory RepositoryFactory) er
 	return nil
 }
 
+// RegisterScoped registers a tenant-aware repository factory with the given
+// repository name; see ScopedRepositoryFactory and DoAs.
+// Return an error if a repository name already registered.
+func (u *UnitOfWork) RegisterScoped(name RepositoryName, factory ScopedRepositoryFactory) error {
+	if u.Has(name) {
+		return ErrRepositoryAlreadyRegistered
+	}
+
+	u.scopedRepositories[name] = factory
+	return nil
+}
+
 // Remove a repository factory with the given repository name.
 // Return an error if the repository name does not registered.
 func (u *UnitOfWork) Remove(name RepositoryName) error {
-	if _, ok := u.repositories[name]; !ok {
+	if !u.Has(name) {
 		return ErrRepositoryNotRegistered
 	}
 
 	delete(u.repositories, name)
+	delete(u.scopedRepositories, name)
 	return nil
 }
 
 // Verify if a repository name already registered.
 // Return true if the repository name is registered, otherwise return false.
 func (u *UnitOfWork) Has(name RepositoryName) bool {
-	_, ok := u.repositories[name]
+	if _, ok := u.repositories[name]; ok {
+		return true
+	}
+
+	_, ok := u.scopedRepositories[name]
 	return ok
 }
 
 // Remove all registered repository name and factories.
 func (u *UnitOfWork) Clear() {
 	u.repositories = make(map[RepositoryName]RepositoryFactory)
+	u.scopedRepositories = make(map[RepositoryName]ScopedRepositoryFactory)
 }
 
 // Executes the provided function (fn) within a transactional context.
@@ -126,22 +575,124 @@ func (u *UnitOfWork) Clear() {
 // The tx parameter in fn gives access to the repositories registered in the Unit Of Work.
 // All operations performed on the repositories are executed within the same transaction.
 //
-// The ctx parameter of Do is passed to fn when it is called.
+// The ctx parameter passed to fn also carries the active *sql.Tx, so code
+// called from fn can resolve it with Conn instead of going through tx.Get.
+// This only applies to the default, *sql.DB-backed Backend.
+//
+// If ctx already carries a transaction, because this Do is called from
+// within another Do's fn, a SAVEPOINT is opened on it instead of starting a
+// new transaction: the outer Do still owns the final commit/rollback, and a
+// failure here only rolls back to this call's savepoint. This lets fn freely
+// call other Do-using functions without committing early or losing the
+// outer work. It requires the Backend's BackendTx to implement SavepointTx;
+// see beginOrNest.
 //
-// If an error occurs, the transaction is rolled back and the error is returned.
-// Otherwise, the transaction is committed, and nil is returned.
-func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx TX) error) error {
-	tx, err := u.db.BeginTx(ctx, nil)
+// opts configures the transaction; see WithIsolation, WithReadOnly and
+// WithRetry.
+//
+// If an error occurs, the transaction (or savepoint) is rolled back and the
+// error is returned. Otherwise, it is committed, and nil is returned, unless
+// an OnCommit hook then fails: its error is returned and the commit stands.
+func (u *UnitOfWork) Do(ctx context.Context, fn func(ctx context.Context, tx TX) error, opts ...Option) error {
+	options := newDoOptions(opts)
+	ctx, scope := resolveScope(ctx, options.scopeValues)
+
+	// A nested Do's own isolation option is ignored by beginOrNest: its
+	// savepoint runs at whatever isolation the outer transaction actually
+	// began at, so report that instead of the option this call requested.
+	isolation := sql.LevelDefault
+	if handle, nested := txHandleFromCtx(ctx); nested {
+		isolation = handle.isolation
+	} else if options.txOpts != nil {
+		isolation = options.txOpts.Isolation
+	}
+
+	ctx, span := u.telemetry.startSpan(ctx, options.name, dbSystemOf(u.backend), isolation)
+	defer span.End()
+
+	rec := newRecordingTX()
+	start := time.Now()
+
+	for attempt := 0; ; attempt++ {
+		err := u.doOnce(ctx, fn, options.txOpts, rec, scope)
+		if err == nil {
+			recordSpanOutcome(span, rec, "committed", attempt, nil)
+			u.telemetry.recordOutcome(options.name, "committed", time.Since(start))
+			return nil
+		}
+
+		if attempt >= options.retries || !isRetryable(err) {
+			recordSpanOutcome(span, rec, "rolled_back", attempt, err)
+			u.telemetry.recordOutcome(options.name, "rolled_back", time.Since(start))
+			return err
+		}
+
+		u.telemetry.recordRetry(options.name)
+		if options.backoff != nil {
+			time.Sleep(options.backoff(attempt))
+		}
+	}
+}
+
+// DoAs is Do run as tenantID: it resolves to a Scope carrying tenantID,
+// seen by ScopedRepositoryFactory and TX.Scope(), and is inherited by any
+// Do or DoAs this call's fn nests, the same way an in-flight transaction is.
+func (u *UnitOfWork) DoAs(ctx context.Context, tenantID TenantID, fn func(ctx context.Context, tx TX) error, opts ...Option) error {
+	scope, _ := scopeFromCtx(ctx)
+	return u.Do(withScope(ctx, scope.withTenant(tenantID)), fn, opts...)
+}
+
+func (u *UnitOfWork) doOnce(ctx context.Context, fn func(ctx context.Context, tx TX) error, txOpts *sql.TxOptions, rec *recordingTX, scope Scope) error {
+	_, nested := txHandleFromCtx(ctx)
+
+	txCtx, commit, rollback, err := beginOrNest(ctx, u.backend, txOpts)
 	if err != nil {
 		return err
 	}
-	defer tx.Rollback()
 
-	err = fn(ctx, NewTransaction(tx, u.repositories))
-	if err != nil {
+	handle, _ := txHandleFromCtx(txCtx)
+
+	// levelHooks collects only the hooks this call's own fn registers, so
+	// its own failure fires/discards just those, not hooks from sibling or
+	// outer Do calls. handle.hooks tracks whichever Do call is currently
+	// innermost; swap it to levelHooks for the duration of this call and
+	// restore it on return, so a nested Do started from within fn picks up
+	// levelHooks as its parent instead of an outer ancestor's.
+	parentHooks := handle.hooks
+	levelHooks := newHookSet()
+	handle.hooks = levelHooks
+	defer func() { handle.hooks = parentHooks }()
+
+	committed := false
+	defer func() {
+		if !committed {
+			rollback()
+			levelHooks.runOnRollback(ctx)
+		}
+	}()
+
+	rec.bind(newTransaction(handle.btx.Handle(), u.repositories, u.scopedRepositories, levelHooks, scope))
+	if err := fn(txCtx, rec); err != nil {
 		return err
 	}
 
-	err = tx.Commit()
-	return err
+	// beforeCommit only runs once, right before the outermost Do's commit
+	// makes everything durable: a nested Do's savepoint release isn't that.
+	if !nested {
+		if err := levelHooks.runBeforeCommit(txCtx); err != nil {
+			return err
+		}
+	}
+
+	if err := commit(); err != nil {
+		return err
+	}
+	committed = true
+
+	if nested {
+		levelHooks.mergeInto(parentHooks)
+		return nil
+	}
+
+	return levelHooks.runOnCommit(ctx)
 }
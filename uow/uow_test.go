@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"errors"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
@@ -13,6 +14,13 @@ import (
 	_ "github.com/go-sql-driver/mysql"
 )
 
+// toTx casts a RepositoryFactory/ScopedRepositoryFactory handle back to
+// *sql.Tx; the test repositories below are only ever built from the default,
+// *sql.DB-backed Backend.
+func toTx(handle any) *sql.Tx {
+	return handle.(*sql.Tx)
+}
+
 // Test types
 type Product struct {
 	id     uuid.UUID
@@ -129,7 +137,7 @@ func Test_Transaction_NewTransaction(t *testing.T) {
 
 	transaction := NewTransaction(tx, repositories)
 	assert.NotNil(t, transaction)
-	assert.Same(t, tx, transaction.tx)
+	assert.Same(t, tx, transaction.handle)
 	assert.Equal(t, repositories, transaction.repositories)
 }
 
@@ -145,12 +153,12 @@ func Test_Transaction_Get(t *testing.T) {
 	_, err = transaction.Get("OrderRepository")
 	assert.ErrorIs(t, ErrRepositoryNotRegistered, err)
 
-	transaction.repositories["ProductRepository"] = func(tx *sql.Tx) Repository {
-		return NewProductRepository(tx)
+	transaction.repositories["ProductRepository"] = func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
 	}
 
-	transaction.repositories["OrderRepository"] = func(tx *sql.Tx) Repository {
-		return NewOrderRepository(tx)
+	transaction.repositories["OrderRepository"] = func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
 	}
 
 	productRepository, err := transaction.Get("ProductRepository")
@@ -169,17 +177,17 @@ func Test_UnitOfWork_NewUnitOfWork(t *testing.T) {
 
 	uow := NewUnitOfWork(db)
 	assert.NotNil(t, uow)
-	assert.Same(t, db, uow.db)
+	assert.Equal(t, &dbBackend{db: db}, uow.backend)
 	assert.NotNil(t, uow.repositories)
 }
 
 func Test_UnitOfWork_Register(t *testing.T) {
-	productRepositoryFactory := func(tx *sql.Tx) Repository {
-		return NewProductRepository(tx)
+	productRepositoryFactory := func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
 	}
 
-	orderRepositoryFactory := func(tx *sql.Tx) Repository {
-		return NewOrderRepository(tx)
+	orderRepositoryFactory := func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
 	}
 
 	uow := NewUnitOfWork(&sql.DB{})
@@ -216,12 +224,12 @@ func Test_UnitOfWork_Remove(t *testing.T) {
 	err = uow.Remove("OrderRepository")
 	assert.ErrorIs(t, err, ErrRepositoryNotRegistered)
 
-	uow.repositories["ProductRepository"] = func(tx *sql.Tx) Repository {
-		return NewProductRepository(tx)
+	uow.repositories["ProductRepository"] = func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
 	}
 
-	uow.repositories["OrderRepository"] = func(tx *sql.Tx) Repository {
-		return NewOrderRepository(tx)
+	uow.repositories["OrderRepository"] = func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
 	}
 
 	err = uow.Remove("ProductRepository")
@@ -242,12 +250,12 @@ func Test_UnitOfWork_Has(t *testing.T) {
 	has = uow.Has("OrderRepository")
 	assert.False(t, has)
 
-	uow.repositories["ProductRepository"] = func(tx *sql.Tx) Repository {
-		return NewProductRepository(tx)
+	uow.repositories["ProductRepository"] = func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
 	}
 
-	uow.repositories["OrderRepository"] = func(tx *sql.Tx) Repository {
-		return NewOrderRepository(tx)
+	uow.repositories["OrderRepository"] = func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
 	}
 
 	has = uow.Has("ProductRepository")
@@ -260,12 +268,12 @@ func Test_UnitOfWork_Has(t *testing.T) {
 func Test_UnitOfWork_Clear(t *testing.T) {
 	uow := NewUnitOfWork(&sql.DB{})
 
-	uow.repositories["ProductRepository"] = func(tx *sql.Tx) Repository {
-		return NewProductRepository(tx)
+	uow.repositories["ProductRepository"] = func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
 	}
 
-	uow.repositories["OrderRepository"] = func(tx *sql.Tx) Repository {
-		return NewOrderRepository(tx)
+	uow.repositories["OrderRepository"] = func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
 	}
 
 	uow.Clear()
@@ -274,6 +282,237 @@ func Test_UnitOfWork_Clear(t *testing.T) {
 	assert.Equal(t, 0, len(uow.repositories))
 }
 
+func Test_Conn_WhenNoTxInCtx(t *testing.T) {
+	db := &sql.DB{}
+
+	conn := Conn(context.Background(), db)
+	assert.Same(t, db, conn)
+}
+
+func Test_Conn_WhenTxInCtx(t *testing.T) {
+	db := &sql.DB{}
+	tx := &sql.Tx{}
+
+	ctx := withTx(context.Background(), &sqlTx{tx: tx}, sql.LevelDefault)
+
+	conn := Conn(ctx, db)
+	assert.Same(t, tx, conn)
+}
+
+func Test_WithTx_WhenBeginFails(t *testing.T) {
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:1)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	txCtx, commit, rollback := WithTx(ctx, db)
+
+	assert.Equal(t, ctx, txCtx)
+	assert.NotNil(t, commit())
+	assert.NotNil(t, rollback())
+}
+
+func Test_WithTx_NestsUnderDo(t *testing.T) {
+	// Connect to db and prepare tables
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("DROP TABLE IF EXISTS orders")
+	require.Nil(t, err)
+
+	_, err = db.Exec("DROP TABLE IF EXISTS products")
+	require.Nil(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE products (
+			id VARCHAR(36) PRIMARY KEY,
+			amount INT(32) UNSIGNED NOT NULL
+	  	);
+	`)
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	uow.Register("ProductRepository", func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
+	})
+
+	product := NewProduct(10)
+	otherProduct := NewProduct(20)
+
+	// Outer Do saves the product. WithTx is then called from within fn,
+	// already running under the outer Do, so it must open a savepoint on
+	// the outer transaction instead of a second, unrelated one.
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		repository, err := tx.Get("ProductRepository")
+		if err != nil {
+			return err
+		}
+
+		productRepository := repository.(*ProductRepository)
+		if err := productRepository.Save(ctx, product); err != nil {
+			return err
+		}
+
+		txCtx, _, rollback := WithTx(ctx, db)
+		assert.Equal(t, ctx, txCtx, "WithTx must reuse ctx, not start an independent transaction")
+
+		otherProductRepository := NewProductRepository(toTx(Conn(txCtx, db)))
+		if err := otherProductRepository.Save(ctx, otherProduct); err != nil {
+			return err
+		}
+
+		return rollback()
+	})
+	require.Nil(t, err)
+
+	// The savepoint rollback inside WithTx must have undone only its own
+	// work: the outer Do's product should have committed, and otherProduct
+	// should not exist.
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		repository, err := tx.Get("ProductRepository")
+		if err != nil {
+			return err
+		}
+
+		productRepository := repository.(*ProductRepository)
+
+		saved, err := productRepository.Get(ctx, product.id)
+		if err != nil {
+			return err
+		}
+
+		if saved.amount != 10 {
+			return errors.New("product saved amount must be 10")
+		}
+
+		if _, err := productRepository.Get(ctx, otherProduct.id); err == nil {
+			return errors.New("other product must not have been saved")
+		}
+
+		return nil
+	})
+	require.Nil(t, err)
+}
+
+func Test_WithIsolation(t *testing.T) {
+	options := newDoOptions([]Option{WithIsolation(sql.LevelSerializable)})
+	require.NotNil(t, options.txOpts)
+	assert.Equal(t, sql.LevelSerializable, options.txOpts.Isolation)
+}
+
+func Test_WithReadOnly(t *testing.T) {
+	options := newDoOptions([]Option{WithReadOnly(true)})
+	require.NotNil(t, options.txOpts)
+	assert.True(t, options.txOpts.ReadOnly)
+}
+
+func Test_WithIsolation_AndWithReadOnly_Combine(t *testing.T) {
+	options := newDoOptions([]Option{WithIsolation(sql.LevelRepeatableRead), WithReadOnly(true)})
+	require.NotNil(t, options.txOpts)
+	assert.Equal(t, sql.LevelRepeatableRead, options.txOpts.Isolation)
+	assert.True(t, options.txOpts.ReadOnly)
+}
+
+func Test_WithRetry(t *testing.T) {
+	backoff := func(attempt int) time.Duration { return time.Duration(attempt) * time.Millisecond }
+
+	options := newDoOptions([]Option{WithRetry(3, backoff)})
+	assert.Equal(t, 3, options.retries)
+	assert.NotNil(t, options.backoff)
+}
+
+func Test_isRetryable(t *testing.T) {
+	assert.False(t, isRetryable(nil))
+	assert.False(t, isRetryable(errors.New("some other error")))
+	assert.True(t, isRetryable(errors.New("Error 1213: Deadlock found when trying to get lock")))
+	assert.True(t, isRetryable(errors.New("pq: could not serialize access due to concurrent update (SQLSTATE 40001)")))
+}
+
+func Test_Do_WhenFnSucceeds(t *testing.T) {
+	// Connect to db and prepare tables
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("DROP TABLE IF EXISTS products")
+	require.Nil(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE products (
+			id VARCHAR(36) PRIMARY KEY,
+			amount INT(32) UNSIGNED NOT NULL
+	  	);
+	`)
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	product := NewProduct(10)
+
+	err = Do(ctx, db, func(ctx context.Context) error {
+		conn := Conn(ctx, db)
+
+		stmt, err := conn.PrepareContext(ctx, "INSERT INTO products (id, amount) VALUES (?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		_, err = stmt.ExecContext(ctx, product.id, product.amount)
+		return err
+	})
+	assert.Nil(t, err)
+
+	var amount uint32
+	err = db.QueryRow("SELECT amount FROM products WHERE id = ?", product.id).Scan(&amount)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(10), amount)
+}
+
+func Test_Do_WhenFnFails(t *testing.T) {
+	// Connect to db and prepare tables
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("DROP TABLE IF EXISTS products")
+	require.Nil(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE products (
+			id VARCHAR(36) PRIMARY KEY,
+			amount INT(32) UNSIGNED NOT NULL
+	  	);
+	`)
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	product := NewProduct(10)
+
+	err = Do(ctx, db, func(ctx context.Context) error {
+		conn := Conn(ctx, db)
+
+		stmt, err := conn.PrepareContext(ctx, "INSERT INTO products (id, amount) VALUES (?, ?)")
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		if _, err = stmt.ExecContext(ctx, product.id, product.amount); err != nil {
+			return err
+		}
+
+		return errors.New("some error after insert")
+	})
+	assert.NotNil(t, err)
+
+	var amount uint32
+	err = db.QueryRow("SELECT amount FROM products WHERE id = ?", product.id).Scan(&amount)
+	assert.NotNil(t, err)
+}
+
 func Test_UnitOfWork_Do_WhenTransactionSucceeds(t *testing.T) {
 	// Connect to db and prepare tables
 	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
@@ -308,12 +547,12 @@ func Test_UnitOfWork_Do_WhenTransactionSucceeds(t *testing.T) {
 	ctx := context.Background()
 	uow := NewUnitOfWork(db)
 
-	uow.Register("ProductRepository", func(tx *sql.Tx) Repository {
-		return NewProductRepository(tx)
+	uow.Register("ProductRepository", func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
 	})
 
-	uow.Register("OrderRepository", func(tx *sql.Tx) Repository {
-		return NewOrderRepository(tx)
+	uow.Register("OrderRepository", func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
 	})
 
 	// Save product, amount = 10
@@ -468,12 +707,12 @@ func Test_UnitOfWork_Do_WhenTransactionFails(t *testing.T) {
 	ctx := context.Background()
 	uow := NewUnitOfWork(db)
 
-	uow.Register("ProductRepository", func(tx *sql.Tx) Repository {
-		return NewProductRepository(tx)
+	uow.Register("ProductRepository", func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
 	})
 
-	uow.Register("OrderRepository", func(tx *sql.Tx) Repository {
-		return NewOrderRepository(tx)
+	uow.Register("OrderRepository", func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
 	})
 
 	// Save product, amount = 10
@@ -592,3 +831,378 @@ func Test_UnitOfWork_Do_WhenTransactionFails(t *testing.T) {
 	})
 	assert.Nil(t, err)
 }
+
+func Test_UnitOfWork_Do_WhenNestedDoFails(t *testing.T) {
+	// Connect to db and prepare tables
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("DROP TABLE IF EXISTS orders")
+	require.Nil(t, err)
+
+	_, err = db.Exec("DROP TABLE IF EXISTS products")
+	require.Nil(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE products (
+			id VARCHAR(36) PRIMARY KEY,
+			amount INT(32) UNSIGNED NOT NULL
+	  	);
+	`)
+	require.Nil(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE orders (
+			id VARCHAR(36) PRIMARY KEY,
+			product_id VARCHAR(36) NOT NULL,
+			amount INT(32) UNSIGNED NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES products(id)
+	  	);
+	`)
+	require.Nil(t, err)
+
+	// Create uow and register repositories
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	uow.Register("ProductRepository", func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
+	})
+
+	uow.Register("OrderRepository", func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
+	})
+
+	product := NewProduct(10)
+
+	// Outer Do saves the product, then nests a Do whose order save fails.
+	// Only the inner savepoint should roll back; the product save must stand.
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		repository, err := tx.Get("ProductRepository")
+		if err != nil {
+			return err
+		}
+
+		productRepository, ok := repository.(*ProductRepository)
+		if !ok {
+			return errors.New("invalid type")
+		}
+
+		if err := productRepository.Save(ctx, product); err != nil {
+			return err
+		}
+
+		// Nested Do: same ctx already carries a transaction, so this opens
+		// a SAVEPOINT instead of starting a new one.
+		innerErr := uow.Do(ctx, func(ctx context.Context, tx TX) error {
+			repository, err := tx.Get("OrderRepository")
+			if err != nil {
+				return err
+			}
+
+			orderRepository, ok := repository.(*OrderRepository)
+			if !ok {
+				return errors.New("invalid type")
+			}
+
+			// An order for a nonexistent product violates the FK constraint.
+			order := NewOrder(uuid.New(), 3)
+			return orderRepository.Save(ctx, order)
+		})
+		assert.NotNil(t, innerErr)
+
+		return nil
+	})
+	assert.Nil(t, err)
+
+	// Verify the product was committed despite the inner failure.
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		repository, err := tx.Get("ProductRepository")
+		if err != nil {
+			return err
+		}
+
+		productRepository, ok := repository.(*ProductRepository)
+		if !ok {
+			return errors.New("invalid type")
+		}
+
+		productSaved, err := productRepository.Get(ctx, product.id)
+		if err != nil {
+			return err
+		}
+
+		if productSaved.amount != 10 {
+			return errors.New("product saved amount must be 10")
+		}
+
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func Test_UnitOfWork_Do_WhenNestedDoFails_DiscardsNestedHooks(t *testing.T) {
+	// Connect to db and prepare tables
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	_, err = db.Exec("DROP TABLE IF EXISTS orders")
+	require.Nil(t, err)
+
+	_, err = db.Exec("DROP TABLE IF EXISTS products")
+	require.Nil(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE products (
+			id VARCHAR(36) PRIMARY KEY,
+			amount INT(32) UNSIGNED NOT NULL
+	  	);
+	`)
+	require.Nil(t, err)
+
+	_, err = db.Exec(`
+		CREATE TABLE orders (
+			id VARCHAR(36) PRIMARY KEY,
+			product_id VARCHAR(36) NOT NULL,
+			amount INT(32) UNSIGNED NOT NULL,
+			FOREIGN KEY (product_id) REFERENCES products(id)
+	  	);
+	`)
+	require.Nil(t, err)
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	uow.Register("ProductRepository", func(handle any) Repository {
+		return NewProductRepository(toTx(handle))
+	})
+
+	uow.Register("OrderRepository", func(handle any) Repository {
+		return NewOrderRepository(toTx(handle))
+	})
+
+	product := NewProduct(10)
+	outerOnCommitCalled := false
+	innerOnCommitCalled := false
+
+	// Outer Do succeeds and registers its own OnCommit; the nested Do also
+	// registers an OnCommit, then fails and rolls back to its savepoint. The
+	// nested hook must never fire, even though the outer Do commits.
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		tx.OnCommit(func(ctx context.Context) error {
+			outerOnCommitCalled = true
+			return nil
+		})
+
+		repository, err := tx.Get("ProductRepository")
+		if err != nil {
+			return err
+		}
+
+		productRepository, ok := repository.(*ProductRepository)
+		if !ok {
+			return errors.New("invalid type")
+		}
+
+		if err := productRepository.Save(ctx, product); err != nil {
+			return err
+		}
+
+		innerErr := uow.Do(ctx, func(ctx context.Context, tx TX) error {
+			tx.OnCommit(func(ctx context.Context) error {
+				innerOnCommitCalled = true
+				return nil
+			})
+
+			repository, err := tx.Get("OrderRepository")
+			if err != nil {
+				return err
+			}
+
+			orderRepository, ok := repository.(*OrderRepository)
+			if !ok {
+				return errors.New("invalid type")
+			}
+
+			// An order for a nonexistent product violates the FK constraint.
+			order := NewOrder(uuid.New(), 3)
+			return orderRepository.Save(ctx, order)
+		})
+		assert.NotNil(t, innerErr)
+
+		return nil
+	})
+	assert.Nil(t, err)
+
+	assert.True(t, outerOnCommitCalled)
+	assert.False(t, innerOnCommitCalled)
+}
+
+func Test_UnitOfWork_Do_WithRetry_WhenRetryableErrorSucceedsEventually(t *testing.T) {
+	// Connect to db
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	attempts := 0
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("Error 1213: Deadlock found when trying to get lock")
+		}
+
+		return nil
+	}, WithRetry(5, func(attempt int) time.Duration { return 0 }))
+	assert.Nil(t, err)
+	assert.Equal(t, 3, attempts)
+}
+
+func Test_UnitOfWork_Do_WithRetry_WhenErrorNotRetryableFailsImmediately(t *testing.T) {
+	// Connect to db
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	attempts := 0
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		attempts++
+		return errors.New("some non-retryable error")
+	}, WithRetry(5, func(attempt int) time.Duration { return 0 }))
+	assert.NotNil(t, err)
+	assert.Equal(t, 1, attempts)
+}
+
+func Test_UnitOfWork_Do_RunsHooksInOrder(t *testing.T) {
+	// Connect to db
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	var order []string
+
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		tx.OnRollback(func(ctx context.Context) error {
+			order = append(order, "onRollback")
+			return nil
+		})
+		tx.OnBeforeCommit(func(ctx context.Context) error {
+			order = append(order, "beforeCommit")
+			return nil
+		})
+		tx.OnCommit(func(ctx context.Context) error {
+			order = append(order, "onCommit")
+			return nil
+		})
+		order = append(order, "fn")
+		return nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"fn", "beforeCommit", "onCommit"}, order)
+}
+
+func Test_UnitOfWork_Do_WhenBeforeCommitFailsRollsBackAndRunsOnRollback(t *testing.T) {
+	// Connect to db
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	beforeCommitErr := errors.New("before commit error")
+	onCommitCalled := false
+	onRollbackCalled := false
+
+	err = uow.Do(ctx, func(ctx context.Context, tx TX) error {
+		tx.OnBeforeCommit(func(ctx context.Context) error {
+			return beforeCommitErr
+		})
+		tx.OnCommit(func(ctx context.Context) error {
+			onCommitCalled = true
+			return nil
+		})
+		tx.OnRollback(func(ctx context.Context) error {
+			onRollbackCalled = true
+			return nil
+		})
+		return nil
+	})
+	assert.ErrorIs(t, err, beforeCommitErr)
+	assert.False(t, onCommitCalled)
+	assert.True(t, onRollbackCalled)
+}
+
+func Test_UnitOfWork_RegisterScoped(t *testing.T) {
+	factory := func(handle any, scope Scope) Repository {
+		return NewProductRepository(toTx(handle))
+	}
+
+	uow := NewUnitOfWork(nil)
+
+	err := uow.RegisterScoped("ScopedProductRepository", factory)
+	assert.Nil(t, err)
+
+	err = uow.RegisterScoped("ScopedProductRepository", factory)
+	assert.ErrorIs(t, err, ErrRepositoryAlreadyRegistered)
+
+	assert.True(t, uow.Has("ScopedProductRepository"))
+
+	err = uow.Remove("ScopedProductRepository")
+	assert.Nil(t, err)
+	assert.False(t, uow.Has("ScopedProductRepository"))
+}
+
+func Test_UnitOfWork_DoAs_PassesScopeToScopedFactory(t *testing.T) {
+	// Connect to db
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	var gotScope Scope
+
+	uow.RegisterScoped("ScopedProductRepository", func(handle any, scope Scope) Repository {
+		gotScope = scope
+		return NewProductRepository(toTx(handle))
+	})
+
+	err = uow.DoAs(ctx, "tenant-1", func(ctx context.Context, tx TX) error {
+		_, err := tx.Get("ScopedProductRepository")
+		assert.Equal(t, TenantID("tenant-1"), tx.Scope().TenantID())
+		return err
+	})
+
+	assert.Nil(t, err)
+	assert.Equal(t, TenantID("tenant-1"), gotScope.TenantID())
+}
+
+func Test_UnitOfWork_Do_NestedUnderDoAs_InheritsScope(t *testing.T) {
+	// Connect to db
+	db, err := sql.Open("mysql", "user:user@tcp(mysql:3306)/test")
+	require.Nil(t, err)
+	defer db.Close()
+
+	ctx := context.Background()
+	uow := NewUnitOfWork(db)
+
+	err = uow.DoAs(ctx, "tenant-1", func(ctx context.Context, tx TX) error {
+		return uow.Do(ctx, func(ctx context.Context, tx TX) error {
+			assert.Equal(t, TenantID("tenant-1"), tx.Scope().TenantID())
+			return nil
+		})
+	})
+	assert.Nil(t, err)
+}